@@ -0,0 +1,260 @@
+// Copyright 2016 - 2022 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to
+// and read from XLSX / XLSM / XLTM files. Supports reading and writing
+// spreadsheet documents generated by Microsoft Excel™ 2007 and later. Supports
+// complex components by high compatibility, and provided streaming API for
+// generating or reading data from a worksheet with huge amounts of data. This
+// library needs Go version 1.15 or later.
+
+package excelize
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// SourceRelationshipComments is the relationship type a worksheet uses to
+// point at its legacy xl/comments%d.xml part.
+const SourceRelationshipComments = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+
+// registerCommentsPart stores a single worksheet's legacy comments part,
+// registers its content type, and adds the relationship that lets the
+// worksheet reference it. Shared by the in-memory Save path and by
+// (*StreamWriter)'s incremental flush so both paths wire up a part the same
+// way.
+func (f *File) registerCommentsPart(sheetIndex int, partName string, data []byte) error {
+	f.Pkg.Store(partName, data)
+	if err := f.addContentTypePart(sheetIndex+1, "comments"); err != nil {
+		return err
+	}
+	_, err := f.addRels(
+		fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheetIndex+1),
+		SourceRelationshipComments,
+		fmt.Sprintf("../comments%d.xml", sheetIndex+1),
+		"",
+	)
+	return err
+}
+
+// AddComment provides the method to add comment in a sheet by given
+// worksheet name, cell reference and format set (such as author and text).
+// Note that the max author length is 255 and the max text length is 32512.
+// For example, add a comment in Sheet1!$A$30:
+//
+//	err := f.AddComment("Sheet1", "A30", excelize.Comment{
+//	    Author: "Excelize: ",
+//	    Text:   "This is a comment.",
+//	})
+func (f *File) AddComment(sheet, cell string, comment Comment) error {
+	comment.Ref = cell
+	return f.addComment(sheet, comment)
+}
+
+// authorRunFont is the font Excel applies to the bold author-name run it
+// automatically prepends to a comment's text, used to write that run.
+// GetComments recognizes the run by its text alone, not by this font.
+var authorRunFont = Font{Bold: true, Family: "Tahoma", Size: 9, Color: "#000000"}
+
+// addComment provides a function to create a legacy comment entry for the
+// given worksheet, used both by AddComment and as the backward-compatible
+// mirror written out for threaded comments.
+func (f *File) addComment(sheet string, comment Comment) error {
+	comments, err := f.commentsReader(sheet, true)
+	if err != nil {
+		return err
+	}
+	authorID := comment.AuthorID
+	found := false
+	for idx, author := range comments.Authors.Author {
+		if author == comment.Author {
+			authorID, found = idx, true
+			break
+		}
+	}
+	if !found {
+		authorID = len(comments.Authors.Author)
+		comments.Authors.Author = append(comments.Authors.Author, comment.Author)
+	}
+	comments.CommentList.Comment = append(comments.CommentList.Comment, xlsxComment{
+		Ref:      comment.Ref,
+		AuthorID: authorID,
+		Text:     f.commentText(comment),
+	})
+	return nil
+}
+
+// commentText builds the xlsxText for a comment, prefixing the user's runs
+// (or plain Text, wrapped as a single run) with the bold author-name run
+// Excel writes automatically, so the round-tripped file renders the same
+// way a native Excel comment would.
+func (f *File) commentText(comment Comment) xlsxText {
+	runs := comment.Runs
+	if len(runs) == 0 && comment.Text != "" {
+		runs = []RichTextRun{{Text: comment.Text}}
+	}
+	text := xlsxText{R: make([]xlsxR, 0, len(runs)+1)}
+	if comment.Author != "" {
+		text.R = append(text.R, richTextRunToXlsxR(RichTextRun{Text: comment.Author + ":", Font: &authorRunFont}))
+	}
+	for _, run := range runs {
+		text.R = append(text.R, richTextRunToXlsxR(run))
+	}
+	return text
+}
+
+// richTextRunToXlsxR converts a RichTextRun, as accepted by SetCellRichText,
+// into the xlsxR shape used by both shared strings and comment text.
+func richTextRunToXlsxR(run RichTextRun) xlsxR {
+	text := run.Text
+	r := xlsxR{T: &text}
+	if run.Font == nil {
+		return r
+	}
+	rPr := &xlsxRPr{}
+	if run.Font.Family != "" {
+		family := run.Font.Family
+		rPr.RFont = &attrValString{Val: &family}
+	}
+	if run.Font.Bold {
+		rPr.B = &attrValBool{Val: true}
+	}
+	if run.Font.Italic {
+		rPr.I = &attrValBool{Val: true}
+	}
+	if run.Font.Size > 0 {
+		size := run.Font.Size
+		rPr.Sz = &attrValFloat{Val: size}
+	}
+	if run.Font.Color != "" {
+		rPr.Color = &xlsxColor{RGB: "FF" + strings.TrimPrefix(strings.ToUpper(run.Font.Color), "#")}
+	}
+	r.RPr = rPr
+	return r
+}
+
+// xlsxRPrToFont converts the rPr of a run read back from a comment's
+// xlsxText into a Font, the inverse of the formatting side of
+// richTextRunToXlsxR, so GetComments returns the same Bold/Italic/Size/Color
+// that AddComment wrote.
+func xlsxRPrToFont(rPr *xlsxRPr) *Font {
+	if rPr == nil {
+		return nil
+	}
+	font := &Font{}
+	if rPr.RFont != nil && rPr.RFont.Val != nil {
+		font.Family = *rPr.RFont.Val
+	}
+	if rPr.B != nil {
+		font.Bold = rPr.B.Val
+	}
+	if rPr.I != nil {
+		font.Italic = rPr.I.Val
+	}
+	if rPr.Sz != nil {
+		font.Size = rPr.Sz.Val
+	}
+	if rPr.Color != nil && rPr.Color.RGB != "" {
+		font.Color = "#" + strings.TrimPrefix(strings.ToUpper(rPr.Color.RGB), "FF")
+	}
+	return font
+}
+
+// commentsWriter marshals every worksheet's buffered legacy comments out to
+// their xl/comments%d.xml parts, registering the content type and per-sheet
+// relationship each part needs. It is invoked from (*File).Save.
+func (f *File) commentsWriter() error {
+	for sheet, comments := range f.Comments {
+		if comments == nil || len(comments.CommentList.Comment) == 0 {
+			continue
+		}
+		sheetIndex, err := f.GetSheetIndex(sheet)
+		if err != nil {
+			return err
+		}
+		data, err := xml.Marshal(comments)
+		if err != nil {
+			return err
+		}
+		partName := fmt.Sprintf("xl/comments%d.xml", sheetIndex+1)
+		if err := f.registerCommentsPart(sheetIndex, partName, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetComments retrieves all comments, including every run of rich text and
+// its formatting, in a worksheet by given worksheet name. The bold
+// author-name run automatically written by Excel is stripped back out of
+// Runs so it isn't duplicated when the comment is edited and re-saved.
+func (f *File) GetComments(sheet string) ([]Comment, error) {
+	comments, err := f.commentsReader(sheet, false)
+	if err != nil || comments == nil {
+		return nil, err
+	}
+	result := make([]Comment, 0, len(comments.CommentList.Comment))
+	for _, c := range comments.CommentList.Comment {
+		author := ""
+		if c.AuthorID < len(comments.Authors.Author) {
+			author = comments.Authors.Author[c.AuthorID]
+		}
+		comment := Comment{Author: author, AuthorID: c.AuthorID, Ref: c.Ref}
+		runs := c.Text.R
+		if len(runs) > 0 && author != "" && runs[0].T != nil && strings.TrimSuffix(*runs[0].T, ":") == author {
+			runs = runs[1:]
+		}
+		for _, r := range runs {
+			run := RichTextRun{Font: xlsxRPrToFont(r.RPr)}
+			if r.T != nil {
+				run.Text = *r.T
+			}
+			comment.Runs = append(comment.Runs, run)
+			comment.Text += run.Text
+		}
+		if comment.Text == "" && c.Text.T != nil {
+			comment.Text = *c.Text.T
+		}
+		result = append(result, comment)
+	}
+	return result, nil
+}
+
+// DeleteComment provides the method to delete comment in a sheet by given
+// worksheet name and cell reference.
+func (f *File) DeleteComment(sheet, cell string) error {
+	comments, err := f.commentsReader(sheet, false)
+	if err != nil || comments == nil {
+		return err
+	}
+	kept := comments.CommentList.Comment[:0]
+	for _, c := range comments.CommentList.Comment {
+		if c.Ref != cell {
+			kept = append(kept, c)
+		}
+	}
+	comments.CommentList.Comment = kept
+	return nil
+}
+
+// commentsReader provides a function to get the pointer to the in-memory
+// legacy comments structure of the worksheet, creating a new one when
+// create is true and none exists yet. It does not read an existing
+// comments part from the package.
+func (f *File) commentsReader(sheet string, create bool) (*xlsxComments, error) {
+	if f.Comments == nil {
+		f.Comments = make(map[string]*xlsxComments)
+	}
+	if comments, ok := f.Comments[sheet]; ok {
+		return comments, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	comments := &xlsxComments{}
+	f.Comments[sheet] = comments
+	return comments, nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2016 - 2022 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to
+// and read from XLSX / XLSM / XLTM files. Supports reading and writing
+// spreadsheet documents generated by Microsoft Excel™ 2007 and later. Supports
+// complex components by high compatibility, and provided streaming API for
+// generating or reading data from a worksheet with huge amounts of data. This
+// library needs Go version 1.15 or later.
+
+package excelize
+
+// Save writes the comments, threaded comments and persons table buffered in
+// memory out to their XML parts, the same way (*StreamWriter).Flush does for
+// a streamed sheet. It only covers the parts added by this package; the rest
+// of the workbook's save pipeline (sheet data, styles, zipping the package
+// up) lives outside these files and is unaffected by it.
+func (f *File) Save() error {
+	if err := f.commentsWriter(); err != nil {
+		return err
+	}
+	return f.threadedCommentsWriter()
+}
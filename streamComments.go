@@ -0,0 +1,275 @@
+// Copyright 2016 - 2022 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to
+// and read from XLSX / XLSM / XLTM files. Supports reading and writing
+// spreadsheet documents generated by Microsoft Excel™ 2007 and later. Supports
+// complex components by high compatibility, and provided streaming API for
+// generating or reading data from a worksheet with huge amounts of data. This
+// library needs Go version 1.15 or later.
+
+package excelize
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// StreamCommentsMemoryThreshold is the default number of buffered comment
+// records a StreamWriter keeps in memory before it spills the remainder to
+// a temporary file, keeping memory bounded when a stream attaches comments
+// to millions of cells.
+const StreamCommentsMemoryThreshold = 4096
+
+// streamComment is the on-disk and in-memory record written for each
+// (*StreamWriter).AddComment or AddThreadedComment call.
+type streamComment struct {
+	Cell     string
+	Comment  Comment
+	Threaded *ThreadedComment
+}
+
+// streamCommentBuffer accumulates streamComment records for a StreamWriter,
+// keeping the first memoryThreshold records in memory and spilling anything
+// past that to a temporary file, one JSON record per line, so attaching
+// comments to a huge stream-written sheet doesn't require holding the whole
+// xlsxComments tree in memory.
+type streamCommentBuffer struct {
+	memoryThreshold int
+	inMemory        []streamComment
+	spillCount      int
+	file            *os.File
+	encoder         *json.Encoder
+}
+
+// add appends a comment record to the buffer, opening the spill file the
+// first time the in-memory threshold is exceeded.
+func (b *streamCommentBuffer) add(c streamComment) error {
+	if b.memoryThreshold <= 0 {
+		b.memoryThreshold = StreamCommentsMemoryThreshold
+	}
+	if len(b.inMemory) < b.memoryThreshold {
+		b.inMemory = append(b.inMemory, c)
+		return nil
+	}
+	if b.file == nil {
+		file, err := os.CreateTemp(os.TempDir(), "excelize-stream-comments-*.jsonl")
+		if err != nil {
+			return err
+		}
+		b.file = file
+		b.encoder = json.NewEncoder(file)
+	}
+	b.spillCount++
+	return b.encoder.Encode(c)
+}
+
+// forEach visits every buffered comment in the order it was added, reading
+// any spilled records back from the temporary file.
+func (b *streamCommentBuffer) forEach(fn func(streamComment) error) error {
+	for _, c := range b.inMemory {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	if b.file == nil {
+		return nil
+	}
+	if _, err := b.file.Seek(0, 0); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(b.file)
+	for decoder.More() {
+		var c streamComment
+		if err := decoder.Decode(&c); err != nil {
+			return err
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close releases the temporary spill file, if one was created.
+func (b *streamCommentBuffer) close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	if err := b.file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// AddComment buffers a legacy comment to be written to the worksheet the
+// StreamWriter is writing when Flush is called, without materializing the
+// full xlsxComments tree in memory. Comments attached this way are written
+// to xl/comments%d.xml, the same as (*File).AddComment does for an
+// in-memory worksheet; as with that in-memory path, rendering the comment
+// indicator itself still requires the VML drawing and legacyDrawing
+// relationship this package does not yet generate.
+func (sw *StreamWriter) AddComment(cell string, c Comment) error {
+	if sw.comments == nil {
+		sw.comments = &streamCommentBuffer{memoryThreshold: StreamCommentsMemoryThreshold}
+	}
+	c.Ref = cell
+	return sw.comments.add(streamComment{Cell: cell, Comment: c})
+}
+
+// AddThreadedComment buffers a threaded comment to be written to the
+// worksheet the StreamWriter is writing when Flush is called, the streaming
+// counterpart to (*File).AddThreadedComment.
+func (sw *StreamWriter) AddThreadedComment(cell string, tc ThreadedComment) error {
+	if sw.comments == nil {
+		sw.comments = &streamCommentBuffer{memoryThreshold: StreamCommentsMemoryThreshold}
+	}
+	return sw.comments.add(streamComment{Cell: cell, Threaded: &tc})
+}
+
+// flushComments drains the buffered comments for the worksheet the
+// StreamWriter is writing directly into the comments and threaded comment
+// XML parts, and is called by Flush once the sheet data itself has been
+// written. It deliberately never calls (*File).AddComment or
+// AddThreadedComment: those populate f.Comments/f.ThreadedComments, which
+// would hold every comment for the sheet in memory at once and defeat the
+// point of streamCommentBuffer spilling past its threshold. Instead each
+// buffered record is encoded straight onto the part's XML buffer as it is
+// read back, so at most one decoded record is resident at a time beyond what
+// the buffer itself keeps. The encoded XML of every record does accumulate
+// in memory, in the part's bytes.Buffer, until the part is stored.
+func (sw *StreamWriter) flushComments() error {
+	if sw.comments == nil {
+		return nil
+	}
+	defer sw.comments.close()
+
+	sheetIndex, err := sw.File.GetSheetIndex(sw.Sheet)
+	if err != nil {
+		return err
+	}
+
+	// First pass: build the legacy author table. This still visits every
+	// buffered record, but keeps only the distinct author names, not the
+	// comments themselves. A root threaded comment (one with no ParentID)
+	// is mirrored into the legacy part the same way (*File).AddThreadedComment
+	// mirrors one in memory, so its author needs a slot here too.
+	authorIndex := map[string]int{}
+	var authors []string
+	addAuthor := func(author string) {
+		if _, ok := authorIndex[author]; !ok {
+			authorIndex[author] = len(authors)
+			authors = append(authors, author)
+		}
+	}
+	err = sw.comments.forEach(func(rec streamComment) error {
+		if rec.Threaded != nil {
+			if rec.Threaded.ParentID == "" {
+				addAuthor(rec.Threaded.AuthorID)
+			}
+			return nil
+		}
+		addAuthor(rec.Comment.Author)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to flush streamed comments: %w", err)
+	}
+
+	var legacyBuf, threadedBuf bytes.Buffer
+	legacyBuf.WriteString(xml.Header)
+	legacyBuf.WriteString(`<comments xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><authors>`)
+	for _, author := range authors {
+		legacyBuf.WriteString("<author>")
+		if err := xml.EscapeText(&legacyBuf, []byte(author)); err != nil {
+			return err
+		}
+		legacyBuf.WriteString("</author>")
+	}
+	legacyBuf.WriteString(`</authors><commentList>`)
+	threadedBuf.WriteString(xml.Header)
+	threadedBuf.WriteString(`<ThreadedComments xmlns="http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments">`)
+
+	legacyEnc, threadedEnc := xml.NewEncoder(&legacyBuf), xml.NewEncoder(&threadedBuf)
+	hasThreaded, threadedCount := false, 0
+	err = sw.comments.forEach(func(rec streamComment) error {
+		if rec.Threaded != nil {
+			hasThreaded = true
+			threadedCount++
+			tc := rec.Threaded
+			if tc.ID == "" {
+				tc.ID = fmt.Sprintf("{%08d-0001-0000-0000-%012d}", 0, threadedCount)
+			}
+			personID, err := sw.File.addPerson(tc.AuthorID)
+			if err != nil {
+				return err
+			}
+			entry := xlsxThreadedComment{
+				Ref:      rec.Cell,
+				DT:       tc.Created,
+				PersonID: personID,
+				ID:       tc.ID,
+				ParentID: tc.ParentID,
+				Text:     tc.Text,
+			}
+			if len(tc.Mentions) > 0 {
+				mentions := make([]xlsxMention, 0, len(tc.Mentions))
+				for i, mention := range tc.Mentions {
+					mentionPersonID, err := sw.File.addPerson(mention)
+					if err != nil {
+						return err
+					}
+					mentions = append(mentions, xlsxMention{MentionPersonID: mentionPersonID, MentionID: strconv.Itoa(i)})
+				}
+				entry.Mentions = &xlsxMentions{Mention: mentions}
+			}
+			if err := threadedEnc.Encode(entry); err != nil {
+				return err
+			}
+			if tc.ParentID != "" {
+				return nil
+			}
+			// Mirror the conversation root into the legacy comments part, the
+			// same as (*File).AddThreadedComment does for an in-memory workbook.
+			comment := Comment{Author: tc.AuthorID, AuthorID: authorIndex[tc.AuthorID], Ref: rec.Cell, Text: tc.Text}
+			return legacyEnc.Encode(xlsxComment{Ref: comment.Ref, AuthorID: comment.AuthorID, Text: sw.File.commentText(comment)})
+		}
+		comment := rec.Comment
+		comment.AuthorID = authorIndex[comment.Author]
+		return legacyEnc.Encode(xlsxComment{Ref: rec.Cell, AuthorID: comment.AuthorID, Text: sw.File.commentText(comment)})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to flush streamed comments: %w", err)
+	}
+	legacyBuf.WriteString(`</commentList></comments>`)
+	threadedBuf.WriteString(`</ThreadedComments>`)
+
+	partName := fmt.Sprintf("xl/comments%d.xml", sheetIndex+1)
+	if err := sw.File.registerCommentsPart(sheetIndex, partName, legacyBuf.Bytes()); err != nil {
+		return err
+	}
+	if hasThreaded {
+		threadedPart := fmt.Sprintf("xl/threadedComments/threadedComment%d.xml", sheetIndex+1)
+		if err := sw.File.registerThreadedCommentsPart(sheetIndex, threadedPart, threadedBuf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return sw.File.writePersonsPart()
+}
+
+// Flush writes the comments and threaded comments buffered by AddComment and
+// AddThreadedComment out to their XML parts. Call it once the sheet itself
+// has finished streaming and before saving the workbook.
+//
+// This only covers comment flushing; it does not close the streamed sheet
+// data or rewrite rows, since that part of StreamWriter lives outside this
+// file and is unaffected by the comment buffering added here.
+func (sw *StreamWriter) Flush() error {
+	return sw.flushComments()
+}
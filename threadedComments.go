@@ -0,0 +1,315 @@
+// Copyright 2016 - 2022 The excelize Authors. All rights reserved. Use of
+// this source code is governed by a BSD-style license that can be found in
+// the LICENSE file.
+//
+// Package excelize providing a set of functions that allow you to write to
+// and read from XLSX / XLSM / XLTM files. Supports reading and writing
+// spreadsheet documents generated by Microsoft Excel™ 2007 and later. Supports
+// complex components by high compatibility, and provided streaming API for
+// generating or reading data from a worksheet with huge amounts of data. This
+// library needs Go version 1.15 or later.
+
+package excelize
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// SourceRelationshipThreadedComment is the relationship type a worksheet
+// uses to point at its xl/threadedComments/threadedComment%d.xml part.
+const SourceRelationshipThreadedComment = "http://schemas.microsoft.com/office/2017/10/relationships/threadedComment"
+
+// ThreadedComment directly maps the threaded comment information, the
+// modern counterpart to Comment used by Excel 365 and stored in
+// xl/threadedComments/threadedComment%d.xml. A ThreadedComment with no
+// ParentID starts a conversation on a cell; every reply carries the ID of
+// the comment it answers in ParentID.
+type ThreadedComment struct {
+	ID       string
+	ParentID string
+	AuthorID string
+	PersonID string
+	Mentions []string
+	Created  string
+	Text     string
+	Replies  []ThreadedComment
+}
+
+// AddThreadedComment provides a method to add a threaded comment to a
+// worksheet by given worksheet name, cell reference and the comment. For a
+// comment that starts a conversation (no ParentID), a legacy Comment
+// mirroring the same author and text is also generated so that applications
+// which only understand xl/comments%d.xml keep showing useful content. For example, add a threaded comment and a reply to it on
+// Sheet1!A1:
+//
+//	err := f.AddThreadedComment("Sheet1", "A1", excelize.ThreadedComment{
+//	    AuthorID: "0", Text: "This is a comment.",
+//	})
+//	err = f.AddThreadedComment("Sheet1", "A1", excelize.ThreadedComment{
+//	    AuthorID: "0", ParentID: "{00000000-0001-0000-0000-000000000001}", Text: "This is a reply.",
+//	})
+func (f *File) AddThreadedComment(sheet, cell string, tc ThreadedComment) error {
+	tcs, err := f.threadedCommentsReader(sheet, true)
+	if err != nil {
+		return err
+	}
+	if tc.ID == "" {
+		tc.ID = fmt.Sprintf("{%08d-0001-0000-0000-%012d}", 0, len(tcs.ThreadedComment)+1)
+	}
+	personID, err := f.addPerson(tc.AuthorID)
+	if err != nil {
+		return err
+	}
+	tc.PersonID = personID
+	entry := xlsxThreadedComment{
+		Ref:      cell,
+		DT:       tc.Created,
+		PersonID: personID,
+		ID:       tc.ID,
+		ParentID: tc.ParentID,
+		Text:     tc.Text,
+	}
+	if len(tc.Mentions) > 0 {
+		mentions := make([]xlsxMention, 0, len(tc.Mentions))
+		for i, mention := range tc.Mentions {
+			mentionPersonID, err := f.addPerson(mention)
+			if err != nil {
+				return err
+			}
+			// mentionId is the 0-based index of the mention within this
+			// comment's text, not the comment's own id.
+			mentions = append(mentions, xlsxMention{MentionPersonID: mentionPersonID, MentionID: strconv.Itoa(i)})
+		}
+		entry.Mentions = &xlsxMentions{Mention: mentions}
+	}
+	tcs.ThreadedComment = append(tcs.ThreadedComment, entry)
+	if tc.ParentID == "" {
+		return f.addComment(sheet, Comment{Author: tc.AuthorID, Ref: cell, Text: tc.Text})
+	}
+	return nil
+}
+
+// GetThreadedComments retrieves all threaded comments in a worksheet by
+// given worksheet name, each reply is nested under the comment named by its
+// ParentID, in the order the replies were written.
+func (f *File) GetThreadedComments(sheet string) ([]ThreadedComment, error) {
+	tcs, err := f.threadedCommentsReader(sheet, false)
+	if err != nil || tcs == nil {
+		return nil, err
+	}
+	// Build the tree on pointers first so a reply attached to a parent that
+	// was already appended to roots is still visible through that pointer,
+	// then convert pointer nodes to values (recursively, so replies-to-replies
+	// are included too) only once the whole tree is wired up.
+	type node struct {
+		tc       ThreadedComment
+		children []*node
+	}
+	byID := make(map[string]*node, len(tcs.ThreadedComment))
+	var rootIDs []string
+	for _, c := range tcs.ThreadedComment {
+		tc := ThreadedComment{
+			ID:       c.ID,
+			ParentID: c.ParentID,
+			PersonID: c.PersonID,
+			AuthorID: f.personDisplayName(c.PersonID),
+			Created:  c.DT,
+			Text:     c.Text,
+		}
+		if c.Mentions != nil {
+			for _, mention := range c.Mentions.Mention {
+				// AddThreadedComment registers each mention by display name
+				// and stores the resulting person id; resolve it back to the
+				// display name here so Mentions round-trips symmetrically.
+				tc.Mentions = append(tc.Mentions, f.personDisplayName(mention.MentionPersonID))
+			}
+		}
+		byID[c.ID] = &node{tc: tc}
+		if c.ParentID == "" {
+			rootIDs = append(rootIDs, c.ID)
+		}
+	}
+	for _, c := range tcs.ThreadedComment {
+		if c.ParentID == "" {
+			continue
+		}
+		if parent, ok := byID[c.ParentID]; ok {
+			parent.children = append(parent.children, byID[c.ID])
+		}
+	}
+	var build func(n *node) ThreadedComment
+	build = func(n *node) ThreadedComment {
+		tc := n.tc
+		for _, child := range n.children {
+			tc.Replies = append(tc.Replies, build(child))
+		}
+		return tc
+	}
+	roots := make([]ThreadedComment, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		roots = append(roots, build(byID[id]))
+	}
+	return roots, nil
+}
+
+// DeleteThreadedComment provides a method to delete a threaded comment and
+// all of its replies from a worksheet by given worksheet name and the ID of
+// the root comment to delete. If id names a conversation root, the legacy
+// comment AddThreadedComment mirrored for backward compatibility is removed
+// along with it.
+func (f *File) DeleteThreadedComment(sheet, id string) error {
+	tcs, err := f.threadedCommentsReader(sheet, false)
+	if err != nil || tcs == nil {
+		return err
+	}
+	remove := map[string]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, c := range tcs.ThreadedComment {
+			if remove[c.ParentID] && !remove[c.ID] {
+				remove[c.ID] = true
+				changed = true
+			}
+		}
+	}
+	var rootRef string
+	for _, c := range tcs.ThreadedComment {
+		if c.ID == id && c.ParentID == "" {
+			rootRef = c.Ref
+		}
+	}
+	kept := tcs.ThreadedComment[:0]
+	for _, c := range tcs.ThreadedComment {
+		if !remove[c.ID] {
+			kept = append(kept, c)
+		}
+	}
+	tcs.ThreadedComment = kept
+	if rootRef != "" {
+		return f.DeleteComment(sheet, rootRef)
+	}
+	return nil
+}
+
+// threadedCommentsReader provides a function to get the pointer to the
+// in-memory threaded comments structure of the worksheet, creating a new one
+// when create is true and none exists yet. It does not read an existing
+// threaded comment part from the package; the part and its relationship are
+// registered when threadedCommentsWriter writes it out.
+func (f *File) threadedCommentsReader(sheet string, create bool) (*xlsxThreadedComments, error) {
+	if f.ThreadedComments == nil {
+		f.ThreadedComments = make(map[string]*xlsxThreadedComments)
+	}
+	if tcs, ok := f.ThreadedComments[sheet]; ok {
+		return tcs, nil
+	}
+	if !create {
+		return nil, nil
+	}
+	tcs := &xlsxThreadedComments{}
+	f.ThreadedComments[sheet] = tcs
+	return tcs, nil
+}
+
+// personsReader provides a function to get the pointer to the workbook-wide
+// person list, used to resolve author identities shared by every threaded
+// comment part, creating it on first use.
+func (f *File) personsReader() *xlsxPersonList {
+	if f.Persons == nil {
+		f.Persons = &xlsxPersonList{}
+	}
+	return f.Persons
+}
+
+// addPerson provides a function to look up or register a person by display
+// name, returning the id that threaded comments and mentions should
+// reference. An existing person with a matching display name is reused
+// instead of creating a duplicate entry.
+func (f *File) addPerson(displayName string) (string, error) {
+	persons := f.personsReader()
+	for _, person := range persons.Person {
+		if person.DisplayName == displayName {
+			return person.ID, nil
+		}
+	}
+	id := fmt.Sprintf("{%08d-0000-0000-0000-%012d}", 0, len(persons.Person)+1)
+	persons.Person = append(persons.Person, xlsxPerson{DisplayName: displayName, ID: id})
+	return id, nil
+}
+
+// personDisplayName provides a function to resolve a person id back to its
+// display name, returning an empty string if the id is unknown.
+func (f *File) personDisplayName(id string) string {
+	if f.Persons == nil {
+		return ""
+	}
+	for _, person := range f.Persons.Person {
+		if person.ID == id {
+			return person.DisplayName
+		}
+	}
+	return ""
+}
+
+// threadedCommentsWriter marshals every worksheet's buffered threaded
+// comments, and the workbook-wide persons table they reference, out to
+// their XML parts, registering the content types and per-sheet
+// relationship each part needs. It is invoked from (*File).Save, the same
+// point the legacy comments parts are written out from.
+func (f *File) threadedCommentsWriter() error {
+	for sheet, tcs := range f.ThreadedComments {
+		if tcs == nil || len(tcs.ThreadedComment) == 0 {
+			continue
+		}
+		sheetIndex, err := f.GetSheetIndex(sheet)
+		if err != nil {
+			return err
+		}
+		data, err := xml.Marshal(tcs)
+		if err != nil {
+			return err
+		}
+		partName := fmt.Sprintf("xl/threadedComments/threadedComment%d.xml", sheetIndex+1)
+		if err := f.registerThreadedCommentsPart(sheetIndex, partName, data); err != nil {
+			return err
+		}
+	}
+	return f.writePersonsPart()
+}
+
+// writePersonsPart marshals the workbook-wide persons table out to
+// xl/persons/person.xml and registers its content type, a no-op if no
+// threaded comment has registered a person yet. Shared by the Save-time
+// writer above and by (*StreamWriter)'s incremental flush.
+func (f *File) writePersonsPart() error {
+	if f.Persons == nil || len(f.Persons.Person) == 0 {
+		return nil
+	}
+	data, err := xml.Marshal(f.Persons)
+	if err != nil {
+		return err
+	}
+	f.Pkg.Store("xl/persons/person.xml", data)
+	return f.addContentTypePart(0, "person")
+}
+
+// registerThreadedCommentsPart stores a single worksheet's threaded comment
+// part, registers its content type, and adds the relationship that lets the
+// worksheet reference it. Shared by the Save-time writer above and by
+// (*StreamWriter)'s incremental flush so both paths wire up a part the same
+// way.
+func (f *File) registerThreadedCommentsPart(sheetIndex int, partName string, data []byte) error {
+	f.Pkg.Store(partName, data)
+	if err := f.addContentTypePart(sheetIndex+1, "threadedComment"); err != nil {
+		return err
+	}
+	_, err := f.addRels(
+		fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheetIndex+1),
+		SourceRelationshipThreadedComment,
+		fmt.Sprintf("../threadedComments/threadedComment%d.xml", sheetIndex+1),
+		"",
+	)
+	return err
+}
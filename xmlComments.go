@@ -44,8 +44,13 @@ type xlsxCommentList struct {
 
 // xlsxComment directly maps the comment element. This element represents a
 // single user entered comment. Each comment shall have an author and can
-// optionally contain richly formatted text.
+// optionally contain richly formatted text. XMLName is set so that encoding
+// a single xlsxComment on its own (as the StreamWriter does) still produces
+// a <comment> element instead of falling back to the Go type name; a
+// comment nested in a xlsxCommentList.Comment field is also named by this
+// XMLName, which matches that field's own "comment" tag.
 type xlsxComment struct {
+	XMLName  xml.Name `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main comment"`
 	Ref      string   `xml:"ref,attr"`
 	AuthorID int      `xml:"authorId,attr"`
 	Text     xlsxText `xml:"text"`
@@ -78,10 +83,80 @@ type formatComment struct {
 	Text   string `json:"text"`
 }
 
-// Comment directly maps the comment information.
+// Comment directly maps the comment information. Runs takes precedence over
+// Text when set, allowing a comment to carry multiple runs of text with
+// independent font formatting, the same way SetCellRichText builds rich text
+// for a cell.
 type Comment struct {
-	Author   string `json:"author"`
-	AuthorID int    `json:"author_id"`
-	Ref      string `json:"ref"`
-	Text     string `json:"text"`
+	Author   string        `json:"author"`
+	AuthorID int           `json:"author_id"`
+	Ref      string        `json:"ref"`
+	Text     string        `json:"text"`
+	Runs     []RichTextRun `json:"runs"`
+}
+
+// xlsxPersonList directly maps the personList element of
+// xl/persons/person.xml. This part holds the list of all unique identities
+// that have authored or been mentioned in a threaded comment somewhere in the
+// workbook; threaded comments reference entries here by id rather than
+// embedding an author name inline.
+type xlsxPersonList struct {
+	XMLName xml.Name     `xml:"http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments personList"`
+	Person  []xlsxPerson `xml:"person"`
+}
+
+// xlsxPerson directly maps a single person element. Id is the value
+// referenced by a threaded comment's personId attribute and by the
+// mentionpersonId attribute of a mention; providerId and userId identify the
+// same person across Office services and are optional for locally authored
+// comments.
+type xlsxPerson struct {
+	DisplayName string `xml:"displayName,attr"`
+	ID          string `xml:"id,attr"`
+	UserID      string `xml:"userId,attr,omitempty"`
+	ProviderID  string `xml:"providerId,attr,omitempty"`
+}
+
+// xlsxThreadedComments directly maps the ThreadedComments element from the
+// namespace http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments,
+// stored per worksheet as xl/threadedComments/threadedComment%d.xml. Unlike
+// the legacy comments part, a threaded comment part can hold whole
+// conversations: a comment that starts a thread has no parentId, while every
+// reply in that thread carries the id of the comment it replies to.
+type xlsxThreadedComments struct {
+	XMLName         xml.Name               `xml:"http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments ThreadedComments"`
+	ThreadedComment []xlsxThreadedComment `xml:"threadedComment"`
+}
+
+// xlsxThreadedComment directly maps a single threadedComment element. Done
+// indicates the comment has been marked resolved in the UI. XMLName is set
+// so that encoding a single xlsxThreadedComment on its own (as the
+// StreamWriter does) still produces a <threadedComment> element instead of
+// falling back to the Go type name; a threadedComment nested in
+// xlsxThreadedComments.ThreadedComment is also named by this XMLName, which
+// matches that field's own "threadedComment" tag.
+type xlsxThreadedComment struct {
+	XMLName  xml.Name      `xml:"http://schemas.microsoft.com/office/spreadsheetml/2018/threadedcomments threadedComment"`
+	Ref      string        `xml:"ref,attr"`
+	DT       string        `xml:"dT,attr,omitempty"`
+	PersonID string        `xml:"personId,attr"`
+	ID       string        `xml:"id,attr"`
+	ParentID string        `xml:"parentId,attr,omitempty"`
+	Done     int           `xml:"done,attr,omitempty"`
+	Text     string        `xml:"text"`
+	Mentions *xlsxMentions `xml:"mentions"`
+}
+
+// xlsxMentions directly maps the mentions element, recording every
+// @mention referenced from a threaded comment's text.
+type xlsxMentions struct {
+	Mention []xlsxMention `xml:"mention"`
+}
+
+// xlsxMention directly maps a single mention element, pairing the id of the
+// person it refers to with the mention's own id. The mentioned text itself
+// is not recorded.
+type xlsxMention struct {
+	MentionPersonID string `xml:"mentionpersonId,attr"`
+	MentionID       string `xml:"mentionId,attr"`
 }